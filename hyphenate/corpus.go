@@ -0,0 +1,100 @@
+package hyphenate
+
+import (
+	"fmt"
+	"sync"
+)
+
+/*
+DetectLanguage is a hook callers can plug into a Corpus so that
+Hyphenate can pick a BCP-47 tag for a given run of text itself,
+rather than requiring the caller to call HyphenateLang with a
+tag it has already worked out.
+*/
+type DetectLanguage func(text string) (tag string)
+
+/*
+Corpus holds a Hyphenator per BCP-47 language tag - e.g. "en-US",
+"de-DE" - so a single value can hyphenate text in more than one
+language. Every Hyphenator in a Corpus shares the hyphen string
+and Options it was created with.
+
+A Corpus is safe for concurrent use by multiple goroutines.
+*/
+type Corpus struct {
+	mu     sync.RWMutex
+	lang   map[string]Hyphenator
+	hyphen string
+	opts   []Option
+	detect DetectLanguage
+}
+
+// NewCorpus returns an empty Corpus. Languages must be loaded
+// into it with LoadLanguage before it can hyphenate anything.
+func NewCorpus(hyphen string, opts ...Option) *Corpus {
+	return &Corpus{
+		lang:   make(map[string]Hyphenator),
+		hyphen: hyphen,
+		opts:   opts,
+	}
+}
+
+/*
+LoadLanguage loads the hyphenation patterns at path and makes
+them available under tag, which should be a BCP-47 language
+tag such as "en-US" or "de-DE". Loading a tag that's already
+loaded replaces it.
+*/
+func (c *Corpus) LoadLanguage(tag, path string) error {
+	h, err := New(path, c.hyphen, nil, c.opts...)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.lang[tag] = h
+	c.mu.Unlock()
+	return nil
+}
+
+// DetectLanguage registers fn as the function Hyphenate uses
+// to choose which loaded language to hyphenate text with.
+func (c *Corpus) DetectLanguage(fn DetectLanguage) {
+	c.mu.Lock()
+	c.detect = fn
+	c.mu.Unlock()
+}
+
+// HyphenateLang hyphenates text using the language loaded
+// under tag. It returns an error if tag hasn't been loaded
+// with LoadLanguage.
+func (c *Corpus) HyphenateLang(tag, text string) (string, error) {
+	c.mu.RLock()
+	h, ok := c.lang[tag]
+	c.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("hyphenate: no language loaded for tag %q", tag)
+	}
+	return h.Hyphenate(text), nil
+}
+
+/*
+Hyphenate hyphenates text using whichever language the
+function registered with DetectLanguage picks for it. It
+returns an error if no DetectLanguage function has been
+registered, or if that function returns a tag that hasn't
+been loaded with LoadLanguage.
+
+For paragraphs that mix languages, split text into
+single-language segments yourself and call HyphenateLang on
+each rather than relying on Hyphenate, which detects one
+language for the whole of text.
+*/
+func (c *Corpus) Hyphenate(text string) (string, error) {
+	c.mu.RLock()
+	detect := c.detect
+	c.mu.RUnlock()
+	if detect == nil {
+		return "", fmt.Errorf("hyphenate: Corpus has no DetectLanguage function registered")
+	}
+	return c.HyphenateLang(detect(text), text)
+}
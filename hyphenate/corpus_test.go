@@ -0,0 +1,33 @@
+package hyphenate
+
+import (
+	"testing"
+)
+
+func TestCorpusHyphenateLangUnknownTag(t *testing.T) {
+	c := NewCorpus("-")
+	if _, err := c.HyphenateLang("en-US", "hello"); err == nil {
+		t.Errorf(`c.HyphenateLang("en-US", "hello") = nil error, wanted one for an unloaded tag`)
+	}
+}
+
+func TestCorpusHyphenateNoDetectLanguage(t *testing.T) {
+	c := NewCorpus("-")
+	if _, err := c.Hyphenate("hello"); err == nil {
+		t.Errorf(`c.Hyphenate("hello") = nil error, wanted one since no DetectLanguage func is registered`)
+	}
+}
+
+func TestCorpusHyphenateUsesDetectedTag(t *testing.T) {
+	c := NewCorpus("-")
+	c.lang["en-US"] = Hyphenator{hyphen: "-", cache: newWordCache(defaultCacheSize)}
+	c.DetectLanguage(func(text string) string { return "en-US" })
+
+	got, err := c.Hyphenate("")
+	if err != nil {
+		t.Fatalf(`c.Hyphenate("") returned error: %v`, err)
+	}
+	if got != "" {
+		t.Errorf(`c.Hyphenate("") = %q, wanted ""`, got)
+	}
+}
@@ -0,0 +1,78 @@
+package hyphenate
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// textRun is a maximal run of either letters or non-letters
+// within a piece of text.
+type textRun struct {
+	text   string
+	isWord bool
+}
+
+/*
+textRuns splits s into alternating runs of letters and
+non-letters, in order, such that concatenating every run's
+text reproduces s exactly.
+*/
+func textRuns(s string) []textRun {
+
+	if s == "" {
+		return nil
+	}
+
+	var runs []textRun
+	start := 0
+	first, _ := utf8.DecodeRuneInString(s)
+	curWord := unicode.IsLetter(first)
+
+	for pos, r := range s {
+		isWord := unicode.IsLetter(r)
+		if isWord == curWord {
+			continue
+		}
+		runs = append(runs, textRun{text: s[start:pos], isWord: curWord})
+		start = pos
+		curWord = isWord
+	}
+	runs = append(runs, textRun{text: s[start:], isWord: curWord})
+
+	return runs
+}
+
+/*
+FulltextHyphenate hyphenates text by walking it rune by rune
+and splitting it into runs of letters and non-letters, rather
+than the strings.Fields-based splitting Hyphenate uses.
+
+Only the letter runs are considered for hyphenation; every
+byte of every other run - punctuation, whitespace of any kind,
+em dashes - and every entity reference such as "&thinsp;" or
+"&#8201;" is copied through unchanged. This correctly
+round-trips input such as "don't", "U.S.A.", non-breaking
+spaces, and em-dash-joined phrases. Hyphenate, by contrast,
+splits text with strings.Fields, so any run of whitespace
+between two words - a non-breaking space included - comes
+back as a single space.
+*/
+func (h Hyphenator) FulltextHyphenate(text string) string {
+	var b strings.Builder
+	for _, er := range entityRuns(text) {
+		if er.isEntity {
+			b.WriteString(er.text)
+			continue
+		}
+		for _, run := range textRuns(er.text) {
+			if !run.isWord {
+				b.WriteString(run.text)
+				continue
+			}
+			word, breakpoints := h.hyphenation(run.text)
+			b.WriteString(insertBreaks(word, h.hyphen, breakpoints))
+		}
+	}
+	return b.String()
+}
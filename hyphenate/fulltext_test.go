@@ -0,0 +1,113 @@
+package hyphenate
+
+import (
+	"testing"
+)
+
+func TestTextRuns(t *testing.T) {
+
+	wants := []struct {
+		s    string
+		want []textRun
+	}{
+		{
+			s:    "",
+			want: nil,
+		},
+		{
+			s: "don't",
+			want: []textRun{
+				{text: "don", isWord: true},
+				{text: "'", isWord: false},
+				{text: "t", isWord: true},
+			},
+		},
+		{
+			s: "U.S.A.",
+			want: []textRun{
+				{text: "U", isWord: true},
+				{text: ".", isWord: false},
+				{text: "S", isWord: true},
+				{text: ".", isWord: false},
+				{text: "A", isWord: true},
+				{text: ".", isWord: false},
+			},
+		},
+		{
+			s: "foo—bar",
+			want: []textRun{
+				{text: "foo", isWord: true},
+				{text: "—", isWord: false},
+				{text: "bar", isWord: true},
+			},
+		},
+		{
+			s: " hello ",
+			want: []textRun{
+				{text: " ", isWord: false},
+				{text: "hello", isWord: true},
+				{text: " ", isWord: false},
+			},
+		},
+	}
+
+	for _, w := range wants {
+		got := textRuns(w.s)
+		if !sameRuns(got, w.want) {
+			t.Errorf("textRuns(%q) = %#v, wanted %#v", w.s, got, w.want)
+		}
+	}
+}
+
+func sameRuns(a, b []textRun) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFulltextHyphenate(t *testing.T) {
+
+	// custom stands in for loaded patterns so this test doesn't
+	// depend on a real hyphenation.Lang being available.
+	h := Hyphenator{
+		hyphen:        "-",
+		minLeft:       2,
+		minRight:      2,
+		minWordLength: 5,
+		cache:         newWordCache(defaultCacheSize),
+		custom: map[string][]string{
+			"hello": {"hel", "lo"},
+			"world": {"world"},
+		},
+	}
+
+	got := h.FulltextHyphenate("hello, world!")
+	want := "hel-lo, world!"
+	if got != want {
+		t.Errorf(`h.FulltextHyphenate("hello, world!") = %q, wanted %q`, got, want)
+	}
+}
+
+func TestFulltextHyphenatePreservesNonWordBytes(t *testing.T) {
+
+	h := Hyphenator{
+		hyphen:        "-",
+		minLeft:       2,
+		minRight:      2,
+		minWordLength: 5,
+		cache:         newWordCache(defaultCacheSize),
+	}
+
+	// No letters at all, so h never has to consult a Lang - this
+	// exercises that every separator byte survives untouched.
+	s := " ... --- ,,, — "
+	if got := h.FulltextHyphenate(s); got != s {
+		t.Errorf("h.FulltextHyphenate(%q) = %q, wanted it unchanged", s, got)
+	}
+}
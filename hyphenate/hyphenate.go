@@ -6,13 +6,18 @@ additional tweaks to better accomodate English text.
 package hyphenate
 
 import (
+	"container/list"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/speedata/hyphenation"
+	"golang.org/x/net/html"
 )
 
 const shyHyphen = "­"
@@ -22,10 +27,113 @@ var (
 	wordSep = "/" + strings.Join(hyphens, "")
 )
 
+// Defaults for the MinLeft, MinRight, and MinWordLength options,
+// matching the behaviour Hyphenate had before those were
+// configurable.
+const (
+	defaultMinLeft       = 2
+	defaultMinRight      = 2
+	defaultMinWordLength = 5
+	defaultCacheSize     = 1024
+)
+
+/*
+Hyphenator is safe for concurrent use by multiple goroutines -
+its per-word cache is guarded by a mutex - so a single instance
+can be shared across, for example, the goroutines serving an
+HTTP server's requests.
+*/
 type Hyphenator struct {
-	hyphen string
-	lang   *hyphenation.Lang
-	custom map[string][]string
+	hyphen        string
+	lang          *hyphenation.Lang
+	custom        map[string][]string
+	substitutions map[string]Substitution
+	cache         *wordCache
+
+	minLeft       int
+	minRight      int
+	minWordLength int
+	cacheSize     int
+}
+
+// Option configures optional behaviour on a Hyphenator. See
+// MinLeft, MinRight, and MinWordLength.
+type Option func(*Hyphenator)
+
+// MinLeft sets the minimum number of runes that must remain
+// before a break point. It defaults to 2.
+func MinLeft(n int) Option {
+	return func(h *Hyphenator) { h.minLeft = n }
+}
+
+// MinRight sets the minimum number of runes that must remain
+// after a break point. It defaults to 2.
+func MinRight(n int) Option {
+	return func(h *Hyphenator) { h.minRight = n }
+}
+
+// MinWordLength sets the word length, in runes, at or below
+// which a word is never hyphenated. It defaults to 5, so by
+// default only words of 6 or more runes are hyphenated.
+func MinWordLength(n int) Option {
+	return func(h *Hyphenator) { h.minWordLength = n }
+}
+
+// CacheSize sets how many words' hyphenation results are kept
+// in the least-recently-used cache. It defaults to 1024. A
+// size of 0 or less disables the cache.
+func CacheSize(n int) Option {
+	return func(h *Hyphenator) { h.cacheSize = n }
+}
+
+/*
+Substitution describes a non-standard hyphenation: breaking a
+word changes its spelling, such as German "backen" -> "bak-ken"
+or Hungarian "asszonnyal" -> "asz-szony-nyal".
+
+Parts are the respelled pieces of the word in order - two for
+a single break like "bak"/"ken", three for two breaks like
+"asz"/"szony"/"nyal", and so on. Joining Parts gives the
+respelled word; a hyphen is inserted between each of them.
+*/
+type Substitution struct {
+	Parts []string
+}
+
+/*
+ParseSubstitution parses the compact, comma-separated form of
+a Substitution's Parts, e.g. "bak,ken" or "asz,szony,nyal".
+*/
+func ParseSubstitution(s string) (Substitution, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 {
+		return Substitution{}, fmt.Errorf(
+			"hyphenate: invalid substitution %q: want comma-separated parts, e.g. \"bak,ken\"", s)
+	}
+	return Substitution{Parts: parts}, nil
+}
+
+// ParseSubstitutions applies ParseSubstitution to every value
+// in m, lower-casing keys to match how Substitutions looks
+// them up.
+func ParseSubstitutions(m map[string]string) (map[string]Substitution, error) {
+	out := make(map[string]Substitution, len(m))
+	for word, s := range m {
+		sub, err := ParseSubstitution(s)
+		if err != nil {
+			return nil, err
+		}
+		out[strings.ToLower(word)] = sub
+	}
+	return out, nil
+}
+
+// Substitutions registers non-standard, spelling-changing
+// hyphenation points for specific words, keyed by lower-case
+// spelling. They take precedence over both the patterns
+// supplied to New and the custom map.
+func Substitutions(m map[string]Substitution) Option {
+	return func(h *Hyphenator) { h.substitutions = m }
 }
 
 /*
@@ -59,8 +167,13 @@ be considered.
 	}
 
 	println(h.Hyphenate("Hello")) // prints "H-ello"
+
+Pass MinLeft, MinRight, and/or MinWordLength to override the
+minimum-length rules Hyphenate otherwise applies by default.
+Pass CacheSize to resize, or disable, the per-word cache that
+makes repeat calls for the same word cheap.
 */
-func New(path, hyphen string, custom map[string][]string) (h Hyphenator, err error) {
+func New(path, hyphen string, custom map[string][]string, opts ...Option) (h Hyphenator, err error) {
 	path, err = filepath.Abs(path)
 	if err != nil {
 		return h, err
@@ -77,6 +190,22 @@ func New(path, hyphen string, custom map[string][]string) (h Hyphenator, err err
 	h.hyphen = hyphen
 	h.lang = lang
 	h.custom = custom
+	h.minLeft = defaultMinLeft
+	h.minRight = defaultMinRight
+	h.minWordLength = defaultMinWordLength
+	h.cacheSize = defaultCacheSize
+	for _, opt := range opts {
+		opt(&h)
+	}
+
+	h.cache = newWordCache(h.cacheSize)
+	for word := range h.custom {
+		h.hyphenation(word)
+	}
+	for word := range h.substitutions {
+		h.hyphenation(word)
+	}
+
 	return h, nil
 }
 
@@ -103,6 +232,233 @@ func subWords(s string) []subWord {
 	return sw
 }
 
+/*
+Word is a single hyphenatable token along with the rune
+indices, relative to Text, at which it may be broken. Leading
+and Trailing hold any surrounding whitespace or grammar that
+was stripped from Text before it was considered for
+hyphenation.
+
+Concatenating Leading, Text (with a hyphen inserted at each of
+Breakpoints), and Trailing for every Word returned by
+HyphenateWords reconstructs the same string Hyphenate would
+have produced, including any whitespace text was surrounded by.
+*/
+type Word struct {
+	Text        string
+	Breakpoints []int
+	Leading     string
+	Trailing    string
+}
+
+/*
+HyphenateWords splits text into Words the same way Hyphenate
+does, but instead of inserting hyphens it reports the rune
+indices at which each word may be broken. This lets callers -
+line-breakers, PDF generators, terminal wrappers - make their
+own decisions about where, or whether, to actually break a
+word.
+
+Words are delineated according to the same criteria used by
+strings.Fields. Leading/trailing whitespace that strings.Fields
+would otherwise discard is preserved on the first and last Word.
+*/
+func (h Hyphenator) HyphenateWords(text string) []Word {
+
+	/*
+		We trim off any whitespace before calling
+		strings.Fields so that we can preserve and
+		restore it on the first/last Word below.
+	*/
+	text, textStart, textEnd := trimSpace(text)
+
+	var words []Word
+
+	for i, s := range strings.Fields(text) {
+		for j, sub := range subWords(s) {
+
+			/*
+				Split any grammer off the word so that it's
+				not factored into our minimum length rules.
+				We save start/end grammar to re-attach later.
+			*/
+			origWord, start, end := trim(sub.word, ",.;:?!()#")
+			end += sub.sep
+
+			/*
+				strings.Fields collapses every run of whitespace
+				down to a single space, so we restore that here.
+			*/
+			if j == 0 && i > 0 {
+				start = " " + start
+			}
+
+			wordText, bps := h.hyphenation(origWord)
+			words = append(words, Word{
+				Text:        wordText,
+				Breakpoints: bps,
+				Leading:     start,
+				Trailing:    end,
+			})
+		}
+	}
+
+	switch {
+	case len(words) > 0:
+		words[0].Leading = textStart + words[0].Leading
+		words[len(words)-1].Trailing += textEnd
+	case textStart != "" || textEnd != "":
+		// text was entirely whitespace; a single Word with no
+		// Text still carries that whitespace so the
+		// Leading+Text+Trailing contract holds.
+		words = append(words, Word{Leading: textStart, Trailing: textEnd})
+	}
+
+	return words
+}
+
+/*
+hyphenation reports the text a word should be rendered as and
+the rune indices, relative to that text, at which h would
+insert a hyphen.
+
+Ordinarily the returned text is just word unchanged, but a
+Substitution registered for word may give back a respelling -
+see Substitution, with word's initial capital, if it has one,
+re-applied to it, since German and similarly-behaved languages capitalise
+words that this feature targets. The cache only ever stores
+case-invariant data - breakpoints, and a Substitution's own
+lower-case respelling - so results are correct no matter which
+casing of a word populates a cache slot first.
+*/
+func (h Hyphenator) hyphenation(word string) (text string, breakpoints []int) {
+
+	key := strings.ToLower(word)
+	if cached, ok := h.cache.get(key); ok {
+		if cached.isSub {
+			return applyCase(word, cached.text), cached.breakpoints
+		}
+		return word, cached.breakpoints
+	}
+
+	// If word breaks with a spelling change, such as German
+	// "backen" -> "bak-ken".
+	if sub, ok := h.substitutions[key]; ok {
+		text = strings.Join(sub.Parts, "")
+		pos := 0
+		for _, p := range sub.Parts[:len(sub.Parts)-1] {
+			pos += strLen(p)
+			breakpoints = append(breakpoints, pos)
+		}
+		h.cache.set(key, cachedResult{text: text, breakpoints: breakpoints, isSub: true})
+		return applyCase(word, text), breakpoints
+	}
+
+	breakpoints = h.breakpoints(word)
+	h.cache.set(key, cachedResult{breakpoints: breakpoints})
+	return word, breakpoints
+}
+
+// applyCase upper-cases the first letter of text when word
+// begins with an upper-case letter, so a Substitution respelling
+// doesn't flatten capitalisation that matters - German
+// capitalises every noun, so "Backen" should respell to
+// "Bakken", not "bakken". Only the first letter is changed: the
+// rest of text keeps its own casing even if word is all
+// upper-case.
+func applyCase(word, text string) string {
+	if text == "" {
+		return text
+	}
+	first, _ := utf8.DecodeRuneInString(word)
+	if !unicode.IsUpper(first) {
+		return text
+	}
+	r, size := utf8.DecodeRuneInString(text)
+	return string(unicode.ToUpper(r)) + text[size:]
+}
+
+/*
+breakpoints reports the rune indices, relative to word, at
+which h would insert a hyphen.
+*/
+func (h Hyphenator) breakpoints(word string) []int {
+
+	// If there's a custom hyphen mapping for this word.
+	if custom, ok := h.custom[strings.ToLower(word)]; ok {
+		var bps []int
+		pos := 0
+		for _, s := range custom[:len(custom)-1] {
+			pos += strLen(s)
+			bps = append(bps, pos)
+		}
+		return bps
+	}
+
+	/*
+		Segment the original word into parts according
+		to the breakpoints we're supplied, slicing from
+		the start of the word.
+	*/
+	raw := h.lang.Hyphenate(word)
+	parts := []string{}
+
+	pos := 0
+	for _, bp := range raw {
+		parts = append(parts, word[pos:bp])
+		pos = bp
+	}
+	if word[pos:] != "" {
+		parts = append(parts, word[pos:])
+	}
+
+	var bps []int
+	seen := 0
+	for _, p := range parts {
+		seen += strLen(p)
+
+		// Don't insert a hyphen if there's already one.
+		if endsWithHyphen(p) {
+			continue
+		}
+
+		/*
+			If word part begins with hyphen reset count
+			on this iteration only. This prevents singular
+			characters after the hyphen from being their
+			own parts.
+		*/
+		partLen := seen
+		if startsWithHyphen(p) {
+			partLen = strLen(p) - 1
+		}
+
+		if h.addHyphen(partLen, word) {
+			bps = append(bps, seen)
+		}
+	}
+
+	return bps
+}
+
+// insertBreaks returns word with hyphen inserted at each rune
+// index in breakpoints.
+func insertBreaks(word, hyphen string, breakpoints []int) string {
+	if len(breakpoints) == 0 {
+		return word
+	}
+	rr := []rune(word)
+	var b strings.Builder
+	pos := 0
+	for _, bp := range breakpoints {
+		b.WriteString(string(rr[pos:bp]))
+		b.WriteString(hyphen)
+		pos = bp
+	}
+	b.WriteString(string(rr[pos:]))
+	return b.String()
+}
+
 /*
 Hyphenate returns a hyphenated version of text, according
 to the parameters provided to New.
@@ -110,113 +466,153 @@ to the parameters provided to New.
 Hyphenate deviates from the hyphenation patterns provided
 to New in the following cases:
 
-- If a word is 5 runes or less it will never be hyphenated.
-- If a word segment is 1 rune it will not be hyphenated.
+- If a word is MinWordLength runes or less it will never be hyphenated.
+- A break point will only be used if it leaves at least MinLeft runes before it and MinRight runes after it.
 - Compound words are treated as separate words - e.g. "part-time" is two 4-letter words.
 - Custom hyphenation patterns for words will override defaults.
 
 Words are delineated according to the same criteria used
 by strings.Fields
+
+Hyphenate is implemented on top of HyphenateWords.
 */
 func (h Hyphenator) Hyphenate(text string) string {
 
-	ww := []string{}
+	var b strings.Builder
+	for _, w := range h.HyphenateWords(text) {
+		b.WriteString(w.Leading)
+		b.WriteString(insertBreaks(w.Text, h.hyphen, w.Breakpoints))
+		b.WriteString(w.Trailing)
+	}
 
-	/*
-		We trim off any whitespace before calling
-		strings.Fields so that we can preserve and
-		restore it later.
-	*/
-	text, textStart, textEnd := trimSpace(text)
+	return b.String()
+}
 
-	for _, s := range strings.Fields(text) {
+// defaultSkipTags are elements whose text content is never
+// hyphenated, regardless of what's passed to HyphenateHTML,
+// because breaking them would change their meaning.
+var defaultSkipTags = []string{"code", "pre", "script", "style"}
 
-		sw := ""
+/*
+HyphenateHTML returns html with its text nodes hyphenated,
+leaving tags, attributes, and entity references untouched.
 
-		for _, sub := range subWords(s) {
+The contents of defaultSkipTags are never hyphenated. skipTags
+may be used to name additional elements - such as a templating
+tag - whose contents should also be left alone.
+*/
+func (h Hyphenator) HyphenateHTML(input string, skipTags []string) (string, error) {
 
-			/*
-				Split any grammer off the word so that it's
-				not factored into our minimum length rules.
-				We save start/end grammar to re-attach later.
+	z := html.NewTokenizer(strings.NewReader(input))
+	var b strings.Builder
+	var skipStack []string
 
-				Note s reassigned repeatedly in a loop below
-				so we keep a copy of the original word.
-			*/
-			origWord, start, end := trim(sub.word, ",.;:?!()#")
-			s := origWord
+	for {
+		switch z.Next() {
 
-			// If there's a custom hyphen mapping for this word.
-			if custom, ok := h.replace(origWord); ok {
-				sw += start + custom + end + sub.sep
-				continue
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return "", err
 			}
+			return b.String(), nil
 
-			/*
-				Segment the original word into parts according
-				to the breakpoints we're supplied. It's easier
-				to slice from the end of the word so we do that.
-			*/
-			breakpoints := h.lang.Hyphenate(s)
-			parts := []string{}
-
-			pos := 0
-			for _, bp := range breakpoints {
-				parts = append(parts, s[pos:bp])
-				pos = bp
-			}
-			if s[pos:] != "" {
-				parts = append(parts, s[pos:])
+		case html.TextToken:
+			if len(skipStack) > 0 {
+				b.Write(z.Raw())
+				continue
 			}
-
-			word := ""
-			seen := 0
-			for _, p := range parts {
-				word += p
-				seen += strLen(p)
-
-				// Don't append a hyphen if there's already one.
-				if endsWithHyphen(p) {
+			for _, run := range entityRuns(string(z.Raw())) {
+				if run.isEntity {
+					b.WriteString(run.text)
 					continue
 				}
+				b.WriteString(h.Hyphenate(run.text))
+			}
 
-				/*
-					If word part begins with hyphen reset count
-					on this iteration only. This prevents singular
-					characters after the hyphen from being their
-					own parts.
-				*/
-				partLen := seen
-				if startsWithHyphen(p) {
-					partLen = strLen(p) - 1
-				}
+		case html.StartTagToken:
+			b.Write(z.Raw())
+			name, _ := z.TagName()
+			tag := string(name)
+			if in(defaultSkipTags, tag) || in(skipTags, tag) {
+				skipStack = append(skipStack, tag)
+			}
 
-				if addHyphen(partLen, origWord) {
-					word += h.hyphen
-				}
+		case html.EndTagToken:
+			b.Write(z.Raw())
+			name, _ := z.TagName()
+			tag := string(name)
+			if len(skipStack) > 0 && skipStack[len(skipStack)-1] == tag {
+				skipStack = skipStack[:len(skipStack)-1]
 			}
 
-			sw += start + word + end + sub.sep
+		default:
+			b.Write(z.Raw())
 		}
-
-		ww = append(ww, sw)
 	}
+}
 
-	return textStart + strings.Join(ww, " ") + textEnd
+// entityRun is a run of text that is, or is not, a single HTML
+// entity reference such as "&thinsp;" or "&#8201;".
+type entityRun struct {
+	text     string
+	isEntity bool
 }
 
-func (h Hyphenator) replace(word string) (replaced string, ok bool) {
-	ss, ok := h.custom[strings.ToLower(word)]
-	if !ok {
-		return replaced, ok
+/*
+entityRuns splits s into entity and non-entity runs so that
+entity references can be left untouched by Hyphenate. Without
+this, a reference like "&thinsp;" would be treated as an
+ordinary, hyphenatable run of word characters and could end up
+with a hyphen spliced into the middle of it.
+*/
+func entityRuns(s string) []entityRun {
+	if s == "" {
+		return nil
 	}
-	pos := 0
-	var parts []string
-	for _, s := range ss {
-		parts = append(parts, word[pos:pos+len(s)])
-		pos += len(s)
+	var runs []entityRun
+	start := 0
+	for i := 0; i < len(s); {
+		if s[i] != '&' {
+			i++
+			continue
+		}
+		n := entityLen(s[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		if i > start {
+			runs = append(runs, entityRun{text: s[start:i]})
+		}
+		runs = append(runs, entityRun{text: s[i : i+n], isEntity: true})
+		i += n
+		start = i
+	}
+	if start < len(s) {
+		runs = append(runs, entityRun{text: s[start:]})
+	}
+	return runs
+}
+
+// entityLen returns the length of the entity reference - "&",
+// then any mix of ASCII letters, digits and "#", then a
+// terminating ";", all within 32 bytes - at the start of s, or 0
+// if s doesn't begin with one.
+func entityLen(s string) int {
+	for i := 1; i < len(s) && i < 32; i++ {
+		switch c := s[i]; {
+		case c == ';':
+			return i + 1
+		case c == '#' || isAlnum(c):
+		default:
+			return 0
+		}
 	}
-	return strings.Join(parts, h.hyphen), ok
+	return 0
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
 }
 
 func startsWithHyphen(s string) bool {
@@ -236,15 +632,15 @@ func endsWithHyphen(s string) bool {
 	return false
 }
 
-func addHyphen(pLen int, full string) bool {
+func (h Hyphenator) addHyphen(pLen int, full string) bool {
 	fLen := strLen(full)
-	if fLen <= 5 {
+	if fLen <= h.minWordLength {
 		return false
 	}
-	if pLen < 2 {
+	if pLen < h.minLeft {
 		return false
 	}
-	if fLen-pLen < 2 {
+	if fLen-pLen < h.minRight {
 		return false
 	}
 	return true
@@ -254,6 +650,88 @@ func strLen(s string) int {
 	return len([]rune(s))
 }
 
+/*
+cachedResult is a hyphenation result kept in a wordCache, keyed
+by a word's lower-case form. Its data must be case-invariant:
+text only ever holds a Substitution's respelling (isSub true),
+never a plain word, since a plain word's casing varies from call
+to call and is returned as-is by hyphenation instead of being
+cached.
+*/
+type cachedResult struct {
+	text        string
+	breakpoints []int
+	isSub       bool
+}
+
+/*
+wordCache is a size-bounded, least-recently-used cache of
+cachedResult keyed by lower-cased word. It's safe for
+concurrent use.
+*/
+type wordCache struct {
+	mu    sync.Mutex
+	cap   int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key   string
+	value cachedResult
+}
+
+func newWordCache(cap int) *wordCache {
+	return &wordCache{
+		cap:   cap,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *wordCache) get(key string) (cachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return cachedResult{}, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*cacheEntry).value, true
+}
+
+func (c *wordCache) set(key string, value cachedResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cap <= 0 {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		el.Value.(*cacheEntry).value = value
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, value: value})
+	if c.ll.Len() > c.cap {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *wordCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll = list.New()
+	c.items = make(map[string]*list.Element)
+}
+
+// ResetCache clears h's word cache, discarding any previously
+// cached hyphenation results.
+func (h Hyphenator) ResetCache() {
+	h.cache.reset()
+}
+
 func trimSpace(s string) (new, start, end string) {
 
 	orig := s
@@ -75,6 +75,350 @@ newFields(%q)
 	}
 }
 
+func TestAddHyphen(t *testing.T) {
+
+	wants := []struct {
+		h    Hyphenator
+		pLen int
+		full string
+		want bool
+	}{
+		{
+			h:    Hyphenator{minLeft: 2, minRight: 2, minWordLength: 5},
+			pLen: 3,
+			full: "friendo",
+			want: true,
+		},
+		{
+			h:    Hyphenator{minLeft: 2, minRight: 2, minWordLength: 5},
+			pLen: 1,
+			full: "friendo",
+			want: false,
+		},
+		{
+			h:    Hyphenator{minLeft: 3, minRight: 3, minWordLength: 5},
+			pLen: 2,
+			full: "friendo",
+			want: false,
+		},
+		{
+			h:    Hyphenator{minLeft: 2, minRight: 2, minWordLength: 8},
+			pLen: 3,
+			full: "friendo",
+			want: false,
+		},
+	}
+
+	for _, w := range wants {
+		if got := w.h.addHyphen(w.pLen, w.full); got != w.want {
+			t.Errorf(
+				"Hyphenator{minLeft: %d, minRight: %d, minWordLength: %d}.addHyphen(%d, %q) = %v, wanted %v",
+				w.h.minLeft, w.h.minRight, w.h.minWordLength, w.pLen, w.full, got, w.want,
+			)
+		}
+	}
+}
+
+func TestHyphenateWordsPreservesSurroundingWhitespace(t *testing.T) {
+
+	h := Hyphenator{
+		hyphen:        "=",
+		minLeft:       2,
+		minRight:      2,
+		minWordLength: 5,
+		cache:         newWordCache(defaultCacheSize),
+		custom: map[string][]string{
+			"composition": {"com", "position"},
+		},
+	}
+
+	words := h.HyphenateWords("  composition  ")
+
+	var got string
+	for _, w := range words {
+		got += w.Leading + insertBreaks(w.Text, h.hyphen, w.Breakpoints) + w.Trailing
+	}
+
+	if want := h.Hyphenate("  composition  "); got != want {
+		t.Errorf("reconstructing HyphenateWords(%q) = %q, wanted %q (what Hyphenate produces)",
+			"  composition  ", got, want)
+	}
+	if got != "  com=position  " {
+		t.Errorf(`reconstructing HyphenateWords("  composition  ") = %q, wanted "  com=position  "`, got)
+	}
+}
+
+func TestHyphenateWordsAllWhitespace(t *testing.T) {
+	h := Hyphenator{hyphen: "-", cache: newWordCache(defaultCacheSize)}
+	words := h.HyphenateWords("   ")
+	var got string
+	for _, w := range words {
+		got += w.Leading + w.Text + w.Trailing
+	}
+	if got != "   " {
+		t.Errorf(`reconstructing HyphenateWords("   ") = %q, wanted "   "`, got)
+	}
+}
+
+func TestInsertBreaks(t *testing.T) {
+
+	wants := []struct {
+		word        string
+		breakpoints []int
+		want        string
+	}{
+		{"friendo", nil, "friendo"},
+		{"friendo", []int{3}, "fri-endo"},
+		{"friendo", []int{2, 5}, "fr-ien-do"},
+	}
+
+	for _, w := range wants {
+		if got := insertBreaks(w.word, "-", w.breakpoints); got != w.want {
+			t.Errorf("insertBreaks(%q, \"-\", %v) = %q, wanted %q",
+				w.word, w.breakpoints, got, w.want)
+		}
+	}
+}
+
+func TestParseSubstitution(t *testing.T) {
+
+	wants := []struct {
+		s    string
+		want []string
+		err  bool
+	}{
+		{
+			s:    "bak,ken",
+			want: []string{"bak", "ken"},
+		},
+		{
+			s:    "asz,szony,nyal",
+			want: []string{"asz", "szony", "nyal"},
+		},
+		{
+			s:   "bak",
+			err: true,
+		},
+	}
+
+	for _, w := range wants {
+		got, err := ParseSubstitution(w.s)
+		if w.err {
+			if err == nil {
+				t.Errorf("ParseSubstitution(%q) = nil error, wanted one", w.s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSubstitution(%q) = %v, wanted nil error", w.s, err)
+			continue
+		}
+		if !sameSlice(got.Parts, w.want) {
+			t.Errorf("ParseSubstitution(%q).Parts = %#v, wanted %#v", w.s, got.Parts, w.want)
+		}
+	}
+}
+
+func TestHyphenationSubstitution(t *testing.T) {
+
+	h := Hyphenator{
+		hyphen:        "-",
+		minLeft:       2,
+		minRight:      2,
+		minWordLength: 5,
+		cache:         newWordCache(defaultCacheSize),
+		substitutions: map[string]Substitution{
+			"backen":     {Parts: []string{"bak", "ken"}},
+			"asszonnyal": {Parts: []string{"asz", "szony", "nyal"}},
+		},
+	}
+
+	text, bps := h.hyphenation("backen")
+	if text != "bakken" || !sameInts(bps, []int{3}) {
+		t.Errorf(`h.hyphenation("backen") = %q, %v, wanted "bakken", [3]`, text, bps)
+	}
+	if got := insertBreaks(text, h.hyphen, bps); got != "bak-ken" {
+		t.Errorf(`insertBreaks(%q, "-", %v) = %q, wanted "bak-ken"`, text, bps, got)
+	}
+
+	text, bps = h.hyphenation("asszonnyal")
+	if text != "aszszonynyal" || !sameInts(bps, []int{3, 8}) {
+		t.Errorf(`h.hyphenation("asszonnyal") = %q, %v, wanted "aszszonynyal", [3 8]`, text, bps)
+	}
+	if got := insertBreaks(text, h.hyphen, bps); got != "asz-szony-nyal" {
+		t.Errorf(`insertBreaks(%q, "-", %v) = %q, wanted "asz-szony-nyal"`, text, bps, got)
+	}
+}
+
+func TestHyphenationCachePreservesCase(t *testing.T) {
+
+	h := Hyphenator{
+		hyphen:        "-",
+		minLeft:       2,
+		minRight:      2,
+		minWordLength: 5,
+		cache:         newWordCache(defaultCacheSize),
+		custom: map[string][]string{
+			"friendly": {"fri", "end", "ly"},
+		},
+	}
+
+	text, bps := h.hyphenation("Friendly")
+	if text != "Friendly" || !sameInts(bps, []int{3, 6}) {
+		t.Errorf(`h.hyphenation("Friendly") = %q, %v, wanted "Friendly", [3 6]`, text, bps)
+	}
+
+	// A later call for the same word with different casing must
+	// not be corrupted by whichever casing first populated the
+	// cache slot for it.
+	text, bps = h.hyphenation("friendly")
+	if text != "friendly" || !sameInts(bps, []int{3, 6}) {
+		t.Errorf(`h.hyphenation("friendly") = %q, %v, wanted "friendly", [3 6]`, text, bps)
+	}
+}
+
+func TestHyphenationSubstitutionAppliesCase(t *testing.T) {
+
+	h := Hyphenator{
+		hyphen:        "-",
+		minLeft:       2,
+		minRight:      2,
+		minWordLength: 5,
+		cache:         newWordCache(defaultCacheSize),
+		substitutions: map[string]Substitution{
+			"backen": {Parts: []string{"bak", "ken"}},
+		},
+	}
+
+	text, bps := h.hyphenation("Backen")
+	if text != "Bakken" || !sameInts(bps, []int{3}) {
+		t.Errorf(`h.hyphenation("Backen") = %q, %v, wanted "Bakken", [3]`, text, bps)
+	}
+	if got := insertBreaks(text, h.hyphen, bps); got != "Bak-ken" {
+		t.Errorf(`insertBreaks(%q, "-", %v) = %q, wanted "Bak-ken"`, text, bps, got)
+	}
+
+	// The cache slot for "backen" is now populated via the
+	// capitalised call above; a lower-case call must still get
+	// the lower-case respelling back.
+	text, bps = h.hyphenation("backen")
+	if text != "bakken" || !sameInts(bps, []int{3}) {
+		t.Errorf(`h.hyphenation("backen") = %q, %v, wanted "bakken", [3]`, text, bps)
+	}
+}
+
+func TestWordCache(t *testing.T) {
+
+	c := newWordCache(2)
+
+	c.set("a", cachedResult{text: "a"})
+	c.set("b", cachedResult{text: "b"})
+
+	if _, ok := c.get("a"); !ok {
+		t.Errorf(`c.get("a") = _, false, wanted true`)
+	}
+
+	// "a" was just touched so "b" should be evicted, not "a".
+	c.set("c", cachedResult{text: "c"})
+
+	if _, ok := c.get("b"); ok {
+		t.Errorf(`c.get("b") = _, true, wanted false (evicted)`)
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Errorf(`c.get("a") = _, false, wanted true`)
+	}
+	if _, ok := c.get("c"); !ok {
+		t.Errorf(`c.get("c") = _, false, wanted true`)
+	}
+
+	c.reset()
+	if _, ok := c.get("a"); ok {
+		t.Errorf(`c.get("a") after reset = _, true, wanted false`)
+	}
+}
+
+func sameInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEntityRuns(t *testing.T) {
+
+	wants := []struct {
+		s    string
+		want []entityRun
+	}{
+		{
+			s:    "",
+			want: nil,
+		},
+		{
+			s: "composition&thinsp;composition",
+			want: []entityRun{
+				{text: "composition"},
+				{text: "&thinsp;", isEntity: true},
+				{text: "composition"},
+			},
+		},
+		{
+			s: "Tom & Jerry",
+			want: []entityRun{
+				{text: "Tom & Jerry"},
+			},
+		},
+		{
+			s: "&#8201;",
+			want: []entityRun{
+				{text: "&#8201;", isEntity: true},
+			},
+		},
+	}
+
+	for _, w := range wants {
+		got := entityRuns(w.s)
+		if len(got) != len(w.want) {
+			t.Errorf("entityRuns(%q) = %#v, wanted %#v", w.s, got, w.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != w.want[i] {
+				t.Errorf("entityRuns(%q) = %#v, wanted %#v", w.s, got, w.want)
+				break
+			}
+		}
+	}
+}
+
+func TestHyphenateHTMLPreservesEntities(t *testing.T) {
+
+	h := Hyphenator{
+		hyphen:        "=",
+		minLeft:       2,
+		minRight:      2,
+		minWordLength: 5,
+		cache:         newWordCache(defaultCacheSize),
+		custom: map[string][]string{
+			"composition": {"composition"},
+		},
+	}
+
+	s := "<p>composition&thinsp;composition</p>"
+	got, err := h.HyphenateHTML(s, nil)
+	if err != nil {
+		t.Fatalf("h.HyphenateHTML(%q, nil) returned error: %v", s, err)
+	}
+	if got != s {
+		t.Errorf("h.HyphenateHTML(%q, nil) = %q, wanted it unchanged", s, got)
+	}
+}
+
 func sameSlice(ss1, ss2 []string) bool {
 	if len(ss1) != len(ss2) {
 		return false